@@ -0,0 +1,64 @@
+package capabilityprobe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheKey identifies one probe result for one model on one backend.
+type cacheKey struct {
+	backend string
+	model   string
+	probe   string
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Cache remembers probe results in memory for a TTL so later reconciliation
+// passes don't re-probe every model already probed. Entries are not
+// persisted: a restart of the provisioner starts with an empty cache.
+type Cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+// NewCache returns a Cache whose entries are considered stale after ttl.
+// A ttl of zero disables caching: every lookup misses.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, entries: make(map[cacheKey]cacheEntry)}
+}
+
+// Run executes p against backend/model, returning a cached result if one
+// is still fresh, and caching the outcome otherwise. Errors are never
+// cached: a transient failure (a momentary 503, a network blip) should be
+// retried next time, not pin a model as lacking a capability for the full
+// TTL.
+func (c *Cache) Run(ctx context.Context, backend, backendURL, apiKey, model string, p Probe) (interface{}, error) {
+	key := cacheKey{backend: backend, model: model, probe: p.Name()}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	c.mu.Unlock()
+
+	value, err := p.Run(ctx, backendURL, apiKey, model)
+	if err != nil {
+		return value, err
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+		c.mu.Unlock()
+	}
+
+	return value, nil
+}
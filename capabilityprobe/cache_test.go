@@ -0,0 +1,89 @@
+package capabilityprobe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeProbe lets a test control what Run returns and count how many times
+// it was actually called (as opposed to served from Cache).
+type fakeProbe struct {
+	name  string
+	key   string
+	calls int
+	fn    func(calls int) (interface{}, error)
+}
+
+func (p *fakeProbe) Name() string         { return p.name }
+func (p *fakeProbe) ModelInfoKey() string { return p.key }
+func (p *fakeProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	p.calls++
+	return p.fn(p.calls)
+}
+
+func TestCacheRunDoesNotCacheErrors(t *testing.T) {
+	probe := &fakeProbe{name: "flaky", key: "supports_flaky", fn: func(calls int) (interface{}, error) {
+		if calls == 1 {
+			return nil, errors.New("transient 503")
+		}
+		return true, nil
+	}}
+	cache := NewCache(time.Hour)
+
+	_, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe)
+	if err == nil {
+		t.Fatal("first Run() = nil error, want the transient failure")
+	}
+
+	value, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe)
+	if err != nil {
+		t.Fatalf("second Run() = %v, want nil (the earlier error must not have been cached)", err)
+	}
+	if value != true {
+		t.Errorf("second Run() = %v, want true", value)
+	}
+	if probe.calls != 2 {
+		t.Errorf("probe called %d times, want 2 (an error result must force a retry, not a cache hit)", probe.calls)
+	}
+}
+
+func TestCacheRunCachesSuccess(t *testing.T) {
+	probe := &fakeProbe{name: "stable", key: "supports_stable", fn: func(calls int) (interface{}, error) {
+		return calls, nil
+	}}
+	cache := NewCache(time.Hour)
+
+	first, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe)
+	if err != nil {
+		t.Fatalf("first Run() returned error: %v", err)
+	}
+	second, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe)
+	if err != nil {
+		t.Fatalf("second Run() returned error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Run() = %v then %v, want the cached value both times", first, second)
+	}
+	if probe.calls != 1 {
+		t.Errorf("probe called %d times, want 1 (a successful result should be served from cache)", probe.calls)
+	}
+}
+
+func TestCacheRunZeroTTLNeverCaches(t *testing.T) {
+	probe := &fakeProbe{name: "uncached", key: "supports_uncached", fn: func(calls int) (interface{}, error) {
+		return calls, nil
+	}}
+	cache := NewCache(0)
+
+	if _, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe); err != nil {
+		t.Fatalf("first Run() returned error: %v", err)
+	}
+	if _, err := cache.Run(context.Background(), "backend", "http://backend", "key", "model", probe); err != nil {
+		t.Fatalf("second Run() returned error: %v", err)
+	}
+	if probe.calls != 2 {
+		t.Errorf("probe called %d times, want 2 (a zero TTL disables caching)", probe.calls)
+	}
+}
@@ -0,0 +1,113 @@
+package capabilityprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// contextLengthProbe empirically discovers the largest prompt a model will
+// accept by binary-searching on prompt size, since backends frequently
+// under- or over-report their real context window.
+type contextLengthProbe struct{}
+
+func (contextLengthProbe) Name() string         { return "context_length" }
+func (contextLengthProbe) ModelInfoKey() string { return "max_input_tokens" }
+
+const (
+	contextProbeInitialWords = 1000
+	contextProbeMaxWords     = 2_000_000
+	contextProbeStepWords    = 50
+	// wordsPerToken is a conservative approximation (no tokenizer is
+	// available here); it only needs to be in the right ballpark since the
+	// discovered value is a safety margin, not an exact token count.
+	wordsPerToken = 0.75
+)
+
+func (contextLengthProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	fits := func(words int) (bool, error) {
+		prompt := strings.Repeat("token ", words)
+		payload := map[string]interface{}{
+			"model": model,
+			"messages": []map[string]string{
+				{"role": "user", "content": prompt},
+			},
+			"max_tokens": 1,
+		}
+		jsonPayload, err := json.Marshal(payload)
+		if err != nil {
+			return false, err
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == 200 {
+			return true, nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, err
+		}
+		if isContextLengthError(body) {
+			return false, nil
+		}
+		return false, fmt.Errorf("non-200 status probing context length: %s, body: %s", resp.Status, string(body))
+	}
+
+	lo := 0
+	hi := contextProbeInitialWords
+	for {
+		ok, err := fits(hi)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		lo = hi
+		hi *= 2
+		if hi > contextProbeMaxWords {
+			return nil, fmt.Errorf("model accepted %d words and the next probe would exceed the %d-word probe cap", lo, contextProbeMaxWords)
+		}
+	}
+
+	for hi-lo > contextProbeStepWords {
+		mid := (lo + hi) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return int(float64(lo) / wordsPerToken), nil
+}
+
+func isContextLengthError(body []byte) bool {
+	lower := strings.ToLower(string(body))
+	for _, marker := range []string{"context_length", "context length", "maximum context", "too many tokens", "context window"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package capabilityprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// embeddingsProbe checks whether a model serves POST /embeddings and
+// returns an actual vector.
+type embeddingsProbe struct{}
+
+func (embeddingsProbe) Name() string         { return "embeddings" }
+func (embeddingsProbe) ModelInfoKey() string { return "supports_embedding" }
+
+func (embeddingsProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	payload := map[string]interface{}{
+		"model": model,
+		"input": "capability probe",
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/embeddings", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := readProbeResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, nil
+	}
+	var result struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, nil
+	}
+	return len(result.Data) > 0 && len(result.Data[0].Embedding) > 0, nil
+}
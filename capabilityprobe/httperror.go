@@ -0,0 +1,34 @@
+package capabilityprobe
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// httpStatusError carries a probe HTTP call's non-200 status, mirroring the
+// type main.go uses for its own backend calls, so a 5xx from a probe
+// surfaces as a retryable error instead of a plain bool.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("probe request failed: status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// readProbeResponse reads resp's body and, for a 5xx status, returns an
+// httpStatusError alongside it. A 4xx/2xx-but-not-200 status is left for
+// the caller to treat as a negative result, not an error: only a 5xx is
+// ambiguous between "transient" and "genuinely unsupported."
+func readProbeResponse(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		return body, &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
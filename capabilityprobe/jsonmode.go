@@ -0,0 +1,71 @@
+package capabilityprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// jsonModeProbe checks whether a model accepts `response_format:
+// {"type":"json_object"}` and actually returns parseable JSON.
+type jsonModeProbe struct{}
+
+func (jsonModeProbe) Name() string         { return "json_mode" }
+func (jsonModeProbe) ModelInfoKey() string { return "supports_json_mode" }
+
+func (jsonModeProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	payload := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "user", "content": `Reply with the JSON object {"ok": true}.`},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := readProbeResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, nil
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, nil
+	}
+	choices, ok := result["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return false, nil
+	}
+	choice, ok := choices[0].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	message, ok := choice["message"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	content, ok := message["content"].(string)
+	if !ok {
+		return false, nil
+	}
+	var probe interface{}
+	return json.Unmarshal([]byte(content), &probe) == nil, nil
+}
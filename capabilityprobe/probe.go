@@ -0,0 +1,54 @@
+// Package capabilityprobe discovers what a backend's model actually
+// supports by exercising it over HTTP, rather than trusting static config.
+// Each capability (tool calling, vision, streaming, ...) is a Probe; a
+// package-level registry, read through Get and Names, tracks the built-ins
+// so callers can run "all of them" or a
+// named subset without a switch statement per capability.
+package capabilityprobe
+
+import "context"
+
+// Probe exercises a single capability against a model and reports what it
+// found. Run's result is stored verbatim under ModelInfoKey() in LiteLLM's
+// model_info, so its concrete type must be JSON-marshalable.
+type Probe interface {
+	// Name identifies the probe in config (the `discovery` list) and cache
+	// keys, e.g. "tool_calling".
+	Name() string
+	// ModelInfoKey is the model_info field the result is written to, e.g.
+	// "supports_function_calling".
+	ModelInfoKey() string
+	// Run calls the backend and returns the discovered capability value.
+	Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error)
+}
+
+var registry = map[string]Probe{}
+
+func register(p Probe) {
+	registry[p.Name()] = p
+}
+
+func init() {
+	register(toolCallingProbe{})
+	register(visionProbe{})
+	register(streamingProbe{})
+	register(jsonModeProbe{})
+	register(embeddingsProbe{})
+	register(contextLengthProbe{})
+}
+
+// Get looks up a built-in probe by name.
+func Get(name string) (Probe, bool) {
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Names returns the names of every built-in probe, for a `discovery: true`
+// config entry that wants all of them.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,67 @@
+package capabilityprobe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// streamingProbe checks whether a model honours `stream: true` and
+// actually emits SSE `data:` frames rather than silently ignoring it.
+type streamingProbe struct{}
+
+func (streamingProbe) Name() string         { return "streaming" }
+func (streamingProbe) ModelInfoKey() string { return "supports_streaming" }
+
+func (streamingProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	payload := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "user", "content": "Count to three."},
+		},
+		"stream": true,
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := readProbeResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		if data != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,76 @@
+package capabilityprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// toolCallingProbe checks whether a model responds to a request offering a
+// function tool with an actual tool_calls entry in its reply.
+type toolCallingProbe struct{}
+
+func (toolCallingProbe) Name() string         { return "tool_calling" }
+func (toolCallingProbe) ModelInfoKey() string { return "supports_function_calling" }
+
+func (toolCallingProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	payload := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "user", "content": "What is the weather?"},
+		},
+		"tools": []map[string]interface{}{
+			{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name": "get_weather",
+					"parameters": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"location": map[string]string{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := readProbeResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, nil
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, nil
+	}
+	if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
+		if choice, ok := choices[0].(map[string]interface{}); ok {
+			if message, ok := choice["message"].(map[string]interface{}); ok {
+				if _, hasToolCalls := message["tool_calls"]; hasToolCalls {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
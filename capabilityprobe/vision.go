@@ -0,0 +1,52 @@
+package capabilityprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// visionProbe checks whether a model accepts an image_url content part
+// without erroring.
+type visionProbe struct{}
+
+func (visionProbe) Name() string         { return "vision" }
+func (visionProbe) ModelInfoKey() string { return "supports_vision" }
+
+func (visionProbe) Run(ctx context.Context, backendURL, apiKey, model string) (interface{}, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base64Image := "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8z/C/HgAGgwJ/lK3Q6wAAAABJRU5ErkJggg=="
+	payload := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "Describe this image"},
+					{"type": "image_url", "image_url": base64Image},
+				},
+			},
+		},
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if _, err := readProbeResponse(resp); err != nil {
+		return false, err
+	}
+	return resp.StatusCode == 200, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestDiscoverySpecUnmarshalYAML(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want DiscoverySpec
+	}{
+		{"bare true", "true", DiscoverySpec{All: true}},
+		{"bare false", "false", DiscoverySpec{All: false}},
+		{"list", "[tool_calling, vision]", DiscoverySpec{All: false, Probes: []string{"tool_calling", "vision"}}},
+		{"empty list", "[]", DiscoverySpec{All: false, Probes: []string{}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got DiscoverySpec
+			if err := yaml.Unmarshal([]byte(c.yaml), &got); err != nil {
+				t.Fatalf("Unmarshal(%q) returned error: %v", c.yaml, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Unmarshal(%q) = %+v, want %+v", c.yaml, got, c.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverySpecUnmarshalYAMLInvalid(t *testing.T) {
+	var d DiscoverySpec
+	if err := yaml.Unmarshal([]byte(`{not: a bool or list}`), &d); err == nil {
+		t.Error("Unmarshal of a mapping should have failed, got nil error")
+	}
+}
+
+func TestDiscoverySpecEnabled(t *testing.T) {
+	cases := []struct {
+		name string
+		spec DiscoverySpec
+		want bool
+	}{
+		{"neither", DiscoverySpec{}, false},
+		{"all", DiscoverySpec{All: true}, true},
+		{"named probes", DiscoverySpec{Probes: []string{"vision"}}, true},
+	}
+	for _, c := range cases {
+		if got := c.spec.Enabled(); got != c.want {
+			t.Errorf("%s: Enabled() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
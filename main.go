@@ -2,16 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/mono-of-pg/ModelProvisioner/capabilityprobe"
 	"gopkg.in/yaml.v2"
 )
 
@@ -20,17 +26,64 @@ type Config struct {
 	Litellm struct {
 		URL string `yaml:"url"`
 	} `yaml:"litellm"`
-	Backends []struct {
-		Name        string `yaml:"name"`
-		URL         string `yaml:"url"`
-		Discovery   bool   `yaml:"discovery"`
-		FilterRegex string `yaml:"filter_regex"`
-		Overrides   []struct {
-			Regex        string                 `yaml:"regex"`
-			Capabilities map[string]interface{} `yaml:"capabilities"`
-		} `yaml:"overrides"`
-		ModelInfoDefaults map[string]interface{} `yaml:"model_info_defaults"`
-	} `yaml:"backends"`
+	Backends []BackendConfig `yaml:"backends"`
+}
+
+// BackendConfig describes one upstream OpenAI-compatible backend.
+type BackendConfig struct {
+	Name              string                 `yaml:"name"`
+	URL               string                 `yaml:"url"`
+	Discovery         DiscoverySpec          `yaml:"discovery"`
+	FilterRegex       string                 `yaml:"filter_regex"`
+	Overrides         []OverrideRule         `yaml:"overrides"`
+	ModelInfoDefaults map[string]interface{} `yaml:"model_info_defaults"`
+}
+
+// OverrideRule forces model_info capabilities for models matching Regex,
+// bypassing whatever discovery would otherwise determine.
+type OverrideRule struct {
+	Regex        string                 `yaml:"regex"`
+	Capabilities map[string]interface{} `yaml:"capabilities"`
+}
+
+// DiscoverySpec is the `discovery` field of a backend. It accepts either a
+// bare bool (true runs every built-in probe, false runs none) or a list of
+// probe names to run a subset.
+type DiscoverySpec struct {
+	All    bool
+	Probes []string
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler so `discovery: true` and
+// `discovery: [tool_calling, vision]` both parse.
+func (d *DiscoverySpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var asBool bool
+	if err := unmarshal(&asBool); err == nil {
+		d.All = asBool
+		d.Probes = nil
+		return nil
+	}
+
+	var asList []string
+	if err := unmarshal(&asList); err != nil {
+		return fmt.Errorf("discovery: expected a bool or a list of probe names: %w", err)
+	}
+	d.All = false
+	d.Probes = asList
+	return nil
+}
+
+// Enabled reports whether any discovery probes should run for this backend.
+func (d DiscoverySpec) Enabled() bool {
+	return d.All || len(d.Probes) > 0
+}
+
+// Names returns the probe names to run, expanding All to every registered probe.
+func (d DiscoverySpec) Names() []string {
+	if d.All {
+		return capabilityprobe.Names()
+	}
+	return d.Probes
 }
 
 // DesiredModelEntry represents a model entry to be added to LiteLLM
@@ -89,9 +142,9 @@ func readConfig(path string) (*Config, error) {
 	return &config, nil
 }
 
-func getModels(backendURL, apiKey string) ([]string, error) {
+func getModels(ctx context.Context, backendURL, apiKey string) ([]string, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", backendURL+"/models", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", backendURL+"/models", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -100,13 +153,17 @@ func getModels(backendURL, apiKey string) ([]string, error) {
 		obfuscatedKey := obfuscateKey(apiKey)
 		log.Printf("Fetching models: URL=%s, Method=GET, Headers=map[Authorization:Bearer %s]", backendURL+"/models", obfuscatedKey)
 	}
+	start := time.Now()
 	resp, err := client.Do(req)
+	backendFetchDuration.WithLabelValues(backendURL).Observe(time.Since(start).Seconds())
 	if err != nil {
+		errorsTotal.WithLabelValues(backendURL, "list").Inc()
 		return nil, err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		errorsTotal.WithLabelValues(backendURL, "list").Inc()
 		return nil, err
 	}
 	if debugMode {
@@ -116,7 +173,8 @@ func getModels(backendURL, apiKey string) ([]string, error) {
 		if debugMode {
 			log.Printf("Error fetching models: Status=%d, Body=%s", resp.StatusCode, string(body))
 		}
-		return nil, fmt.Errorf("non-200 status: %s, body: %s", resp.Status, string(body))
+		errorsTotal.WithLabelValues(backendURL, "list").Inc()
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	var result struct {
 		Data []struct {
@@ -137,9 +195,9 @@ func getModels(backendURL, apiKey string) ([]string, error) {
 	return models, nil
 }
 
-func getCurrentModels(litellmURL, litellmApiKey string) ([]CurrentModelEntry, error) {
+func getCurrentModels(ctx context.Context, litellmURL, litellmApiKey string) ([]CurrentModelEntry, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", litellmURL+"/model/info", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", litellmURL+"/model/info", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -150,11 +208,13 @@ func getCurrentModels(litellmURL, litellmApiKey string) ([]CurrentModelEntry, er
 	}
 	resp, err := client.Do(req)
 	if err != nil {
+		errorsTotal.WithLabelValues("litellm", "list").Inc()
 		return nil, err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		errorsTotal.WithLabelValues("litellm", "list").Inc()
 		return nil, err
 	}
 	if debugMode {
@@ -164,7 +224,8 @@ func getCurrentModels(litellmURL, litellmApiKey string) ([]CurrentModelEntry, er
 		if debugMode {
 			log.Printf("Error fetching current models: Status=%d, Body=%s", resp.StatusCode, string(body))
 		}
-		return nil, fmt.Errorf("non-200 status: %s, body: %s", resp.Status, string(body))
+		errorsTotal.WithLabelValues("litellm", "list").Inc()
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	var result struct {
 		Data []CurrentModelEntry `json:"data"`
@@ -181,7 +242,7 @@ func getCurrentModels(litellmURL, litellmApiKey string) ([]CurrentModelEntry, er
 	return result.Data, nil
 }
 
-func addModel(litellmURL, litellmApiKey string, entry DesiredModelEntry) error {
+func addModel(ctx context.Context, litellmURL, litellmApiKey string, entry DesiredModelEntry) error {
 	payload, err := json.Marshal(entry)
 	if err != nil {
 		return err
@@ -191,7 +252,7 @@ func addModel(litellmURL, litellmApiKey string, entry DesiredModelEntry) error {
 		log.Printf("Adding model: URL=%s, Method=POST, Headers=map[Content-Type:application/json Authorization:Bearer %s], Payload=%s", litellmURL+"/model/new", obfuscatedKey, string(payload))
 	}
 	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("POST", litellmURL+"/model/new", bytes.NewBuffer(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", litellmURL+"/model/new", bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
@@ -199,26 +260,30 @@ func addModel(litellmURL, litellmApiKey string, entry DesiredModelEntry) error {
 	req.Header.Set("Authorization", "Bearer "+litellmApiKey)
 	resp, err := client.Do(req)
 	if err != nil {
+		errorsTotal.WithLabelValues(entry.LitellmParams.ApiBase, "add").Inc()
 		return err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		errorsTotal.WithLabelValues(entry.LitellmParams.ApiBase, "add").Inc()
 		return err
 	}
 	if resp.StatusCode != 200 {
 		if debugMode {
 			log.Printf("Error adding model %s: Status=%d, Body=%s", entry.ModelName, resp.StatusCode, string(body))
 		}
-		return fmt.Errorf("non-200 status: %s, body: %s", resp.Status, string(body))
+		errorsTotal.WithLabelValues(entry.LitellmParams.ApiBase, "add").Inc()
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	if debugMode {
 		log.Printf("Successfully added model %s: Status=200, Body=%s", entry.ModelName, string(body))
 	}
+	modelsAddedTotal.WithLabelValues(entry.LitellmParams.ApiBase).Inc()
 	return nil
 }
 
-func removeModel(litellmURL, litellmApiKey string, id string) error {
+func removeModel(ctx context.Context, litellmURL, litellmApiKey, backend, id string) error {
 	payload := DeleteModelPayload{ID: id}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -229,7 +294,7 @@ func removeModel(litellmURL, litellmApiKey string, id string) error {
 		log.Printf("Removing model: URL=%s, Method=POST, Headers=map[Content-Type:application/json Authorization:Bearer %s], Payload=%s", litellmURL+"/model/delete", obfuscatedKey, string(jsonPayload))
 	}
 	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("POST", litellmURL+"/model/delete", bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", litellmURL+"/model/delete", bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return err
 	}
@@ -237,104 +302,71 @@ func removeModel(litellmURL, litellmApiKey string, id string) error {
 	req.Header.Set("Authorization", "Bearer "+litellmApiKey)
 	resp, err := client.Do(req)
 	if err != nil {
+		errorsTotal.WithLabelValues(backend, "remove").Inc()
 		return err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		errorsTotal.WithLabelValues(backend, "remove").Inc()
 		return err
 	}
 	if resp.StatusCode != 200 {
 		if debugMode {
 			log.Printf("Error removing model with ID %s: Status=%d, Body=%s", id, resp.StatusCode, string(body))
 		}
-		return fmt.Errorf("non-200 status: %s, body: %s", resp.Status, string(body))
+		errorsTotal.WithLabelValues(backend, "remove").Inc()
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	if debugMode {
 		log.Printf("Successfully removed model with ID %s: Status=200, Body=%s", id, string(body))
 	}
+	modelsRemovedTotal.WithLabelValues(backend).Inc()
 	return nil
 }
 
-func testToolUse(backendURL, apiKey, model string) bool {
-	client := &http.Client{Timeout: 10 * time.Second}
-	payload := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": "What is the weather?"},
-		},
-		"tools": []map[string]interface{}{
-			{
-				"type": "function",
-				"function": map[string]interface{}{
-					"name": "get_weather",
-					"parameters": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"location": map[string]string{"type": "string"},
-						},
-					},
-				},
-			},
-		},
-	}
-	jsonPayload, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	resp, err := client.Do(req)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		var result map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
-			if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-				if choice, ok := choices[0].(map[string]interface{}); ok {
-					if message, ok := choice["message"].(map[string]interface{}); ok {
-						if _, hasToolCalls := message["tool_calls"]; hasToolCalls {
-							return true
-						}
-					}
-				}
+// runDiscoveryProbes runs every probe named by spec against model, caching
+// results in opts.ProbeCache, and returns the discovered model_info values
+// keyed by each probe's ModelInfoKey. known is the model_info already set
+// by ModelInfoDefaults/Overrides; a probe whose ModelInfoKey is already in
+// known is skipped entirely, since overrides are meant to short-circuit
+// discovery, not just win a merge after an expensive probe already ran.
+// Each probe call is rate-limited and retried the same way every other
+// outbound backend call is.
+func runDiscoveryProbes(ctx context.Context, opts *ReconcilerOptions, backend BackendConfig, backendURL, apiKey, model string, known map[string]interface{}) map[string]interface{} {
+	results := make(map[string]interface{})
+	for _, name := range backend.Discovery.Names() {
+		probe, ok := capabilityprobe.Get(name)
+		if !ok {
+			log.Printf("Unknown capability probe %q for backend %s", name, backend.Name)
+			continue
+		}
+		if _, exists := known[probe.ModelInfoKey()]; exists {
+			continue
+		}
+		var value interface{}
+		err := withRetry(ctx, backendURL, "probe:"+name, opts.MaxAttempts, func() error {
+			if err := opts.Limiters.Wait(ctx, backendURL); err != nil {
+				return err
 			}
+			v, err := opts.ProbeCache.Run(ctx, backend.URL, backendURL, apiKey, model, probe)
+			if err != nil {
+				return err
+			}
+			value = v
+			return nil
+		})
+		if err != nil {
+			log.Printf("Probe %s failed for %s on %s: %v", name, model, backend.Name, err)
+			errorsTotal.WithLabelValues(backendURL, "probe").Inc()
+			continue
 		}
+		results[probe.ModelInfoKey()] = value
 	}
-	return false
+	return results
 }
 
-func testVision(backendURL, apiKey, model string) bool {
-	client := &http.Client{Timeout: 10 * time.Second}
-	base64Image := "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8z/C/HgAGgwJ/lK3Q6wAAAABJRU5ErkJggg=="
-	payload := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]interface{}{
-			{
-				"role": "user",
-				"content": []map[string]interface{}{
-					{"type": "text", "text": "Describe this image"},
-					{"type": "image_url", "image_url": base64Image},
-				},
-			},
-		},
-	}
-	jsonPayload, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", backendURL+"/chat/completions", bytes.NewBuffer(jsonPayload))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	resp, err := client.Do(req)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-	return resp.StatusCode == 200
-}
-
-func applyOverrides(model string, overrides []struct {
-	Regex        string                 `yaml:"regex"`
-	Capabilities map[string]interface{} `yaml:"capabilities"`
-}) map[string]interface{} {
+func applyOverrides(model string, overrides []OverrideRule) map[string]interface{} {
 	for _, override := range overrides {
 		if matched, _ := regexp.MatchString(override.Regex, model); matched {
 			return override.Capabilities
@@ -343,170 +375,432 @@ func applyOverrides(model string, overrides []struct {
 	return nil
 }
 
-func main() {
-	sleepIntervalStr := os.Getenv("SLEEP_INTERVAL")
-	sleepInterval, err := strconv.Atoi(sleepIntervalStr)
+// ReconcilerOptions bundles the knobs that control how a reconciliation
+// pass spreads its work: how many backends are discovered concurrently,
+// how many LiteLLM mutations run concurrently, retry behavior, the
+// per-backend rate limiters, and the capability-probe result cache.
+type ReconcilerOptions struct {
+	DiscoveryConcurrency int
+	LitellmConcurrency   int
+	MaxAttempts          int
+	Limiters             *backendLimiters
+	ProbeCache           *capabilityprobe.Cache
+	// DryRun, when true, computes the plan and prints it to stdout instead
+	// of calling addModel/removeModel.
+	DryRun bool
+}
+
+// reconcile runs a single discovery-diff-apply pass against the configured
+// backends and LiteLLM. It respects ctx cancellation on every outbound HTTP
+// call so a shutdown signal aborts in-flight requests instead of leaving a
+// half-applied /model/new or /model/delete behind.
+func reconcile(ctx context.Context, config *Config, litellmApiKey string, opts *ReconcilerOptions) (Plan, error) {
+	start := time.Now()
+	defer func() { reconcileDuration.Observe(time.Since(start).Seconds()) }()
+
+	var currentModels []CurrentModelEntry
+	err := withRetry(ctx, "litellm", "list", opts.MaxAttempts, func() error {
+		if err := opts.Limiters.Wait(ctx, config.Litellm.URL); err != nil {
+			return err
+		}
+		models, err := getCurrentModels(ctx, config.Litellm.URL, litellmApiKey)
+		if err != nil {
+			return err
+		}
+		currentModels = models
+		return nil
+	})
 	if err != nil {
-		sleepInterval = 60
+		return Plan{}, fmt.Errorf("getting current models from LiteLLM: %w", err)
 	}
 
-	log.Println("Starting LiteLLM ModelProvisioner (https://github.com/mono-of-pg/ModelProvisioner)")
-	for {
-		config, err := readConfig("/etc/config/config.yaml")
-		if err != nil {
-			log.Println("Error reading config:", err)
-			time.Sleep(time.Duration(sleepInterval) * time.Second)
-			continue
+	configuredBackends := make(map[string]bool)
+	for _, backend := range config.Backends {
+		configuredBackends[backend.URL] = true
+	}
+
+	desiredModels := discoverBackends(ctx, config.Backends, opts)
+	plan := computePlan(desiredModels, currentModels, configuredBackends)
+
+	desiredCountByBackend := make(map[string]int)
+	for _, entry := range desiredModels {
+		desiredCountByBackend[entry.LitellmParams.ApiBase]++
+	}
+	currentCountByBackend := make(map[string]int)
+	for _, entry := range currentModels {
+		if configuredBackends[entry.LitellmParams.ApiBase] {
+			currentCountByBackend[entry.LitellmParams.ApiBase]++
 		}
+	}
+	for _, backend := range config.Backends {
+		desiredModelsGauge.WithLabelValues(backend.URL).Set(float64(desiredCountByBackend[backend.URL]))
+		currentModelsGauge.WithLabelValues(backend.URL).Set(float64(currentCountByBackend[backend.URL]))
+	}
 
-		litellmApiKey, err := ioutil.ReadFile("/etc/secrets/litellm")
+	if opts.DryRun {
+		// Augment plan.Add with the same capability-probe model_info a real
+		// apply would write, so the preview doesn't understate it.
+		for _, entry := range plan.Add {
+			augmentWithDiscovery(ctx, config, entry, opts)
+		}
+		if err := writePlan(os.Stdout, plan); err != nil {
+			return plan, fmt.Errorf("writing plan: %w", err)
+		}
+		return plan, nil
+	}
+
+	applyChanges(ctx, config, litellmApiKey, plan.Add, plan.Remove, opts)
+
+	return plan, nil
+}
+
+// discoverBackends fans out getModels (and the overrides/defaults that
+// shape a DesiredModelEntry) across opts.DiscoveryConcurrency goroutines,
+// one backend at a time, and collects every backend's results once all of
+// them finish.
+func discoverBackends(ctx context.Context, backends []BackendConfig, opts *ReconcilerOptions) []DesiredModelEntry {
+	results := make(chan []DesiredModelEntry, len(backends))
+	tasks := make([]func(), 0, len(backends))
+	for _, b := range backends {
+		backend := b
+		tasks = append(tasks, func() {
+			results <- discoverOneBackend(ctx, backend, opts)
+		})
+	}
+	runPool(opts.DiscoveryConcurrency, tasks)
+	close(results)
+
+	var desiredModels []DesiredModelEntry
+	for entries := range results {
+		desiredModels = append(desiredModels, entries...)
+	}
+	return desiredModels
+}
+
+// discoverOneBackend lists a single backend's models (rate-limited and
+// retried) and turns the ones that pass FilterRegex into DesiredModelEntry
+// values with ModelInfoDefaults and Overrides applied.
+func discoverOneBackend(ctx context.Context, backend BackendConfig, opts *ReconcilerOptions) []DesiredModelEntry {
+	apiKeyPath := "/etc/secrets/" + backend.Name
+	apiKey, err := ioutil.ReadFile(apiKeyPath)
+	if err != nil {
+		if debugMode {
+			log.Printf("API key not found for backend %s, using BLANK", backend.Name)
+		}
+		apiKey = []byte("BLANK")
+	}
+
+	var filterRegex *regexp.Regexp
+	if backend.FilterRegex != "" {
+		filterRegex, err = regexp.Compile(backend.FilterRegex)
 		if err != nil {
-			log.Println("Error reading LiteLLM API key:", err)
-			time.Sleep(time.Duration(sleepInterval) * time.Second)
-			continue
+			log.Printf("Invalid filter regex for backend %s: %v", backend.Name, err)
+			return nil
 		}
+	}
 
-		currentModels, err := getCurrentModels(config.Litellm.URL, string(litellmApiKey))
+	var models []string
+	err = withRetry(ctx, backend.URL, "list", opts.MaxAttempts, func() error {
+		if err := opts.Limiters.Wait(ctx, backend.URL); err != nil {
+			return err
+		}
+		m, err := getModels(ctx, backend.URL, string(apiKey))
 		if err != nil {
-			log.Println("Error getting current models from LiteLLM:", err)
-			time.Sleep(time.Duration(sleepInterval) * time.Second)
-			continue
+			return err
 		}
+		models = m
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error getting models from %s: %v", backend.Name, err)
+		return nil
+	}
 
-		var desiredModels []DesiredModelEntry
-		configuredBackends := make(map[string]bool)
-		for _, backend := range config.Backends {
-			configuredBackends[backend.URL] = true
-			apiKeyPath := "/etc/secrets/" + backend.Name
-			apiKey, err := ioutil.ReadFile(apiKeyPath)
-			if err != nil {
-				if debugMode {
-					log.Printf("API key not found for backend %s, using BLANK", backend.Name)
-				}
-				apiKey = []byte("BLANK")
-			}
+	var entries []DesiredModelEntry
+	for _, model := range models {
+		if filterRegex != nil && !filterRegex.MatchString(model) {
+			continue // Skip models that don't match the regex
+		}
+		entry := DesiredModelEntry{
+			ModelName: model,
+			LitellmParams: struct {
+				Model   string `json:"model"`
+				ApiBase string `json:"api_base"`
+				ApiKey  string `json:"api_key"`
+			}{
+				Model:   "openai/" + model,
+				ApiBase: backend.URL,
+				ApiKey:  string(apiKey),
+			},
+			ModelInfo: make(map[string]interface{}),
+		}
 
-			var filterRegex *regexp.Regexp
-			if backend.FilterRegex != "" {
-				filterRegex, err = regexp.Compile(backend.FilterRegex)
-				if err != nil {
-					log.Printf("Invalid filter regex for backend %s: %v", backend.Name, err)
-					continue
-				}
-			}
+		for k, v := range backend.ModelInfoDefaults {
+			entry.ModelInfo[k] = v
+		}
 
-			models, err := getModels(backend.URL, string(apiKey))
-			if err != nil {
-				log.Printf("Error getting models from %s: %v", backend.Name, err)
-				continue
+		if overrideCaps := applyOverrides(model, backend.Overrides); overrideCaps != nil {
+			for k, v := range overrideCaps {
+				entry.ModelInfo[k] = v
 			}
+		}
 
-			for _, model := range models {
-				if filterRegex != nil && !filterRegex.MatchString(model) {
-					continue // Skip models that don't match the regex
-				}
-				entry := DesiredModelEntry{
-					ModelName: model,
-					LitellmParams: struct {
-						Model   string `json:"model"`
-						ApiBase string `json:"api_base"`
-						ApiKey  string `json:"api_key"`
-					}{
-						Model:   "openai/" + model,
-						ApiBase: backend.URL,
-						ApiKey:  string(apiKey),
-					},
-					ModelInfo: make(map[string]interface{}),
-				}
-
-				for k, v := range backend.ModelInfoDefaults {
-					entry.ModelInfo[k] = v
-				}
-
-				if overrideCaps := applyOverrides(model, backend.Overrides); overrideCaps != nil {
-					for k, v := range overrideCaps {
-						entry.ModelInfo[k] = v
-					}
-				}
-
-				desiredModels = append(desiredModels, entry)
-			}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// applyChanges fans toAdd and toRemove out across opts.LitellmConcurrency
+// goroutines, since a slow LiteLLM response for one model shouldn't block
+// applying any of the others.
+func applyChanges(ctx context.Context, config *Config, litellmApiKey string, toAdd []DesiredModelEntry, toRemove []CurrentModelEntry, opts *ReconcilerOptions) {
+	tasks := make([]func(), 0, len(toAdd)+len(toRemove))
+
+	for _, e := range toAdd {
+		entry := e
+		tasks = append(tasks, func() { applyAdd(ctx, config, litellmApiKey, entry, opts) })
+	}
+	for _, e := range toRemove {
+		entry := e
+		tasks = append(tasks, func() { applyRemove(ctx, config.Litellm.URL, litellmApiKey, entry, opts) })
+	}
+
+	runPool(opts.LitellmConcurrency, tasks)
+}
+
+// augmentWithDiscovery runs entry's backend's capability probes, if any are
+// configured, and merges their results into entry.ModelInfo. It is shared
+// by applyAdd and the DRY_RUN plan path so a preview reflects the same
+// model_info a real apply would write.
+func augmentWithDiscovery(ctx context.Context, config *Config, entry DesiredModelEntry, opts *ReconcilerOptions) {
+	backendURL := entry.LitellmParams.ApiBase
+	model := entry.ModelName
+	apiKey := entry.LitellmParams.ApiKey
+
+	var backendConfig *BackendConfig
+	for _, b := range config.Backends {
+		if b.URL == backendURL {
+			backendConfig = &b
+			break
 		}
+	}
 
-		currentSet := make(map[string]CurrentModelEntry)
-		for _, entry := range currentModels {
-			if configuredBackends[entry.LitellmParams.ApiBase] {
-				key := fmt.Sprintf("%s|%s", entry.ModelName, entry.LitellmParams.ApiBase)
-				currentSet[key] = entry
+	if backendConfig != nil && backendConfig.Discovery.Enabled() {
+		for key, value := range runDiscoveryProbes(ctx, opts, *backendConfig, backendURL, apiKey, model, entry.ModelInfo) {
+			if _, exists := entry.ModelInfo[key]; !exists {
+				entry.ModelInfo[key] = value
 			}
 		}
+	}
+}
 
-		desiredSet := make(map[string]DesiredModelEntry)
-		for _, entry := range desiredModels {
-			key := fmt.Sprintf("%s|%s", entry.ModelName, entry.LitellmParams.ApiBase)
-			desiredSet[key] = entry
-		}
+func applyAdd(ctx context.Context, config *Config, litellmApiKey string, entry DesiredModelEntry, opts *ReconcilerOptions) {
+	backendURL := entry.LitellmParams.ApiBase
+	augmentWithDiscovery(ctx, config, entry, opts)
 
-		var toAdd []DesiredModelEntry
-		for key, entry := range desiredSet {
-			if _, exists := currentSet[key]; !exists {
-				toAdd = append(toAdd, entry)
-			}
+	log.Printf("Adding model %s from %s", entry.ModelName, entry.LitellmParams.ApiBase)
+	err := withRetry(ctx, backendURL, "add", opts.MaxAttempts, func() error {
+		if err := opts.Limiters.Wait(ctx, config.Litellm.URL); err != nil {
+			return err
 		}
+		return addModel(ctx, config.Litellm.URL, litellmApiKey, entry)
+	})
+	if err != nil {
+		log.Printf("Error adding model %s: %v", entry.ModelName, err)
+	}
+}
 
-		var toRemove []CurrentModelEntry
-		for key, entry := range currentSet {
-			if _, exists := desiredSet[key]; !exists {
-				toRemove = append(toRemove, entry)
-			}
+func applyRemove(ctx context.Context, litellmURL, litellmApiKey string, entry CurrentModelEntry, opts *ReconcilerOptions) {
+	log.Printf("Removing model %s from %s with ID %s", entry.ModelName, entry.LitellmParams.ApiBase, entry.ModelInfo.ID)
+	err := withRetry(ctx, entry.LitellmParams.ApiBase, "remove", opts.MaxAttempts, func() error {
+		if err := opts.Limiters.Wait(ctx, litellmURL); err != nil {
+			return err
 		}
+		return removeModel(ctx, litellmURL, litellmApiKey, entry.LitellmParams.ApiBase, entry.ModelInfo.ID)
+	})
+	if err != nil {
+		log.Printf("Error removing model %s with ID %s: %v", entry.ModelName, entry.ModelInfo.ID, err)
+	}
+}
+
+// lastPassOK reports whether the most recently completed reconciliation
+// pass succeeded, and backs the /readyz endpoint.
+var lastPassOK atomic.Bool
+
+// runPass reads the config and secret, then runs reconcile, logging any
+// setup error the same way the old inline loop did. It is the unit of work
+// the main loop repeats on each tick, and the unit main() waits to finish
+// before exiting on a shutdown signal. The returned Plan is only meaningful
+// when err is nil.
+func runPass(ctx context.Context, opts *ReconcilerOptions) (Plan, error) {
+	config, err := readConfig("/etc/config/config.yaml")
+	if err != nil {
+		log.Println("Error reading config:", err)
+		lastPassOK.Store(false)
+		return Plan{}, err
+	}
+
+	litellmApiKey, err := ioutil.ReadFile("/etc/secrets/litellm")
+	if err != nil {
+		log.Println("Error reading LiteLLM API key:", err)
+		lastPassOK.Store(false)
+		return Plan{}, err
+	}
 
-		for _, entry := range toAdd {
-			backendURL := entry.LitellmParams.ApiBase
-			model := entry.ModelName
-			apiKey := entry.LitellmParams.ApiKey
-
-			var backendConfig *struct {
-				Name        string `yaml:"name"`
-				URL         string `yaml:"url"`
-				Discovery   bool   `yaml:"discovery"`
-				FilterRegex string `yaml:"filter_regex"`
-				Overrides   []struct {
-					Regex        string                 `yaml:"regex"`
-					Capabilities map[string]interface{} `yaml:"capabilities"`
-				} `yaml:"overrides"`
+	plan, err := reconcile(ctx, config, string(litellmApiKey), opts)
+	if err != nil {
+		log.Println("Error during reconciliation:", err)
+		lastPassOK.Store(false)
+		return Plan{}, err
+	}
+	lastPassOK.Store(true)
+	return plan, nil
+}
+
+const (
+	configWatchDir  = "/etc/config"
+	secretsWatchDir = "/etc/secrets"
+)
+
+// watchDir watches dir for changes and sends a (coalesced, non-blocking)
+// notification on trigger for each one. Kubernetes ConfigMap/Secret mounts
+// apply updates by swapping a hidden "..data" symlink, which replaces the
+// directory fsnotify thinks it's watching, so the watch is re-added after
+// every event to keep following the live directory.
+func watchDir(ctx context.Context, dir string, trigger chan<- struct{}) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Error creating watcher for %s: %v", dir, err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("Error watching %s: %v", dir, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
 			}
-			for _, b := range config.Backends {
-				if b.URL == backendURL {
-					backendConfig = &b
-					break
-				}
+			if debugMode {
+				log.Printf("Watch event on %s: %s", dir, event)
 			}
-
-			if backendConfig != nil && backendConfig.Discovery {
-				if _, exists := entry.ModelInfo["supports_function_calling"]; !exists {
-					entry.ModelInfo["supports_function_calling"] = testToolUse(backendURL, apiKey, model)
-				}
-				if _, exists := entry.ModelInfo["supports_vision"]; !exists {
-					entry.ModelInfo["supports_vision"] = testVision(backendURL, apiKey, model)
-				}
+			if err := watcher.Add(dir); err != nil {
+				log.Printf("Error re-adding watch on %s: %v", dir, err)
 			}
-
-			log.Printf("Adding model %s from %s", entry.ModelName, entry.LitellmParams.ApiBase)
-			err := addModel(config.Litellm.URL, string(litellmApiKey), entry)
-			if err != nil {
-				log.Printf("Error adding model %s: %v", entry.ModelName, err)
+			select {
+			case trigger <- struct{}{}:
+			default:
 			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Watcher error on %s: %v", dir, err)
 		}
+	}
+}
 
-		for _, entry := range toRemove {
-			log.Printf("Removing model %s from %s with ID %s", entry.ModelName, entry.LitellmParams.ApiBase, entry.ModelInfo.ID)
-			err := removeModel(config.Litellm.URL, string(litellmApiKey), entry.ModelInfo.ID)
-			if err != nil {
-				log.Printf("Error removing model %s with ID %s: %v", entry.ModelName, entry.ModelInfo.ID, err)
-			}
+func main() {
+	sleepIntervalStr := os.Getenv("SLEEP_INTERVAL")
+	sleepInterval, err := strconv.Atoi(sleepIntervalStr)
+	if err != nil {
+		sleepInterval = 60
+	}
+
+	probeCacheTTL, err := strconv.Atoi(os.Getenv("PROBE_CACHE_TTL_SECONDS"))
+	if err != nil {
+		probeCacheTTL = 86400 // 24h
+	}
+
+	discoveryConcurrency, err := strconv.Atoi(os.Getenv("DISCOVERY_CONCURRENCY"))
+	if err != nil {
+		discoveryConcurrency = 5
+	}
+	litellmConcurrency, err := strconv.Atoi(os.Getenv("LITELLM_CONCURRENCY"))
+	if err != nil {
+		litellmConcurrency = 5
+	}
+	maxAttempts, err := strconv.Atoi(os.Getenv("RETRY_MAX_ATTEMPTS"))
+	if err != nil {
+		maxAttempts = 3
+	}
+	rateLimitQPS, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_QPS"), 64)
+	if err != nil {
+		rateLimitQPS = 5
+	}
+	rateLimitBurst, err := strconv.Atoi(os.Getenv("RATE_LIMIT_BURST"))
+	if err != nil {
+		rateLimitBurst = 5
+	}
+
+	opts := &ReconcilerOptions{
+		DiscoveryConcurrency: discoveryConcurrency,
+		LitellmConcurrency:   litellmConcurrency,
+		MaxAttempts:          maxAttempts,
+		Limiters:             newBackendLimiters(rateLimitQPS, rateLimitBurst),
+		ProbeCache:           capabilityprobe.NewCache(time.Duration(probeCacheTTL) * time.Second),
+		DryRun:               os.Getenv("DRY_RUN") == "true",
+	}
+	planOnce := os.Getenv("PLAN_ONCE") == "true"
+	if planOnce {
+		// A pre-deploy gate must never mutate state, regardless of DRY_RUN.
+		opts.DryRun = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if planOnce {
+		plan, err := runPass(ctx, opts)
+		if err != nil {
+			log.Println("Error during reconciliation:", err)
+			os.Exit(1)
 		}
+		if plan.HasChanges() {
+			os.Exit(2)
+		}
+		os.Exit(0)
+	}
+
+	log.Println("Starting LiteLLM ModelProvisioner (https://github.com/mono-of-pg/ModelProvisioner)")
 
-		time.Sleep(time.Duration(sleepInterval) * time.Second)
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9090"
+	}
+	metricsServer := &http.Server{Addr: metricsAddr, Handler: metricsHandler(lastPassOK.Load)}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+	defer metricsServer.Close()
+
+	ticker := time.NewTicker(time.Duration(sleepInterval) * time.Second)
+	defer ticker.Stop()
+
+	// trigger is a coalescing channel: however many filesystem events land
+	// while a pass is running, at most one extra reconciliation is queued.
+	trigger := make(chan struct{}, 1)
+	go watchDir(ctx, configWatchDir, trigger)
+	go watchDir(ctx, secretsWatchDir, trigger)
+
+	for {
+		runPass(ctx, opts)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Shutdown signal received, exiting after current pass")
+			return
+		case <-ticker.C:
+		case <-trigger:
+			log.Println("Config or secret change detected, reconciling immediately")
+		}
 	}
 }
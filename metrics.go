@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	modelsAddedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "model_provisioner_models_added_total",
+		Help: "Number of models added to LiteLLM, by backend.",
+	}, []string{"backend"})
+
+	modelsRemovedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "model_provisioner_models_removed_total",
+		Help: "Number of models removed from LiteLLM, by backend.",
+	}, []string{"backend"})
+
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "model_provisioner_errors_total",
+		Help: "Number of failed operations, by backend and operation (add|remove|list|probe).",
+	}, []string{"backend", "op"})
+
+	reconcileDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "model_provisioner_reconcile_duration_seconds",
+		Help:    "Time taken to complete a full reconciliation pass.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	backendFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "model_provisioner_backend_fetch_duration_seconds",
+		Help:    "Latency of GET /models against a backend.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend"})
+
+	desiredModelsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "model_provisioner_desired_models",
+		Help: "Number of models desired for a backend according to the current config.",
+	}, []string{"backend"})
+
+	currentModelsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "model_provisioner_current_models",
+		Help: "Number of models currently registered in LiteLLM for a backend.",
+	}, []string{"backend"})
+)
+
+// metricsHandler returns a mux serving /metrics, /healthz and /readyz.
+// ready is polled on every /readyz request so it reflects the outcome of
+// the most recent reconciliation pass.
+func metricsHandler(ready func() bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
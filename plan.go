@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Plan is the result of diffing desired model state against what LiteLLM
+// currently has registered for the configured backends.
+type Plan struct {
+	Add       []DesiredModelEntry
+	Remove    []CurrentModelEntry
+	Unchanged int
+}
+
+// HasChanges reports whether applying this plan would add or remove any models.
+func (p Plan) HasChanges() bool {
+	return len(p.Add) > 0 || len(p.Remove) > 0
+}
+
+// computePlan diffs desired against current, restricted to models on
+// configuredBackends, and returns what would need to change. It has no
+// side effects, which makes it unit-testable without a running backend.
+func computePlan(desired []DesiredModelEntry, current []CurrentModelEntry, configuredBackends map[string]bool) Plan {
+	currentSet := make(map[string]CurrentModelEntry)
+	for _, entry := range current {
+		if configuredBackends[entry.LitellmParams.ApiBase] {
+			key := fmt.Sprintf("%s|%s", entry.ModelName, entry.LitellmParams.ApiBase)
+			currentSet[key] = entry
+		}
+	}
+
+	desiredSet := make(map[string]DesiredModelEntry)
+	for _, entry := range desired {
+		key := fmt.Sprintf("%s|%s", entry.ModelName, entry.LitellmParams.ApiBase)
+		desiredSet[key] = entry
+	}
+
+	var plan Plan
+	for key, entry := range desiredSet {
+		if _, exists := currentSet[key]; exists {
+			plan.Unchanged++
+		} else {
+			plan.Add = append(plan.Add, entry)
+		}
+	}
+	for key, entry := range currentSet {
+		if _, exists := desiredSet[key]; !exists {
+			plan.Remove = append(plan.Remove, entry)
+		}
+	}
+	return plan
+}
+
+// planAddJSON and planRemoveJSON are the wire shapes for a DRY_RUN plan,
+// trimmed to what an operator needs to see (no api_key).
+type planAddJSON struct {
+	ModelName string                 `json:"model_name"`
+	ApiBase   string                 `json:"api_base"`
+	ModelInfo map[string]interface{} `json:"model_info"`
+}
+
+type planRemoveJSON struct {
+	ModelName string `json:"model_name"`
+	ApiBase   string `json:"api_base"`
+	ID        string `json:"id"`
+}
+
+type planJSON struct {
+	Add       []planAddJSON    `json:"add"`
+	Remove    []planRemoveJSON `json:"remove"`
+	Unchanged int              `json:"unchanged"`
+}
+
+// writePlan prints plan to w as the structured JSON document DRY_RUN emits.
+func writePlan(w io.Writer, plan Plan) error {
+	out := planJSON{
+		Add:       make([]planAddJSON, 0, len(plan.Add)),
+		Remove:    make([]planRemoveJSON, 0, len(plan.Remove)),
+		Unchanged: plan.Unchanged,
+	}
+	for _, entry := range plan.Add {
+		out.Add = append(out.Add, planAddJSON{
+			ModelName: entry.ModelName,
+			ApiBase:   entry.LitellmParams.ApiBase,
+			ModelInfo: entry.ModelInfo,
+		})
+	}
+	for _, entry := range plan.Remove {
+		out.Remove = append(out.Remove, planRemoveJSON{
+			ModelName: entry.ModelName,
+			ApiBase:   entry.LitellmParams.ApiBase,
+			ID:        entry.ModelInfo.ID,
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func desiredEntry(model, apiBase string) DesiredModelEntry {
+	var e DesiredModelEntry
+	e.ModelName = model
+	e.LitellmParams.ApiBase = apiBase
+	return e
+}
+
+func currentEntry(model, apiBase, id string) CurrentModelEntry {
+	var e CurrentModelEntry
+	e.ModelName = model
+	e.LitellmParams.ApiBase = apiBase
+	e.ModelInfo.ID = id
+	return e
+}
+
+func TestComputePlanAddsMissingAndRemovesStale(t *testing.T) {
+	desired := []DesiredModelEntry{
+		desiredEntry("gpt-4", "http://a"),
+		desiredEntry("gpt-5", "http://a"),
+	}
+	current := []CurrentModelEntry{
+		currentEntry("gpt-4", "http://a", "id-1"),
+		currentEntry("gpt-3", "http://a", "id-2"),
+	}
+	configured := map[string]bool{"http://a": true}
+
+	plan := computePlan(desired, current, configured)
+
+	if plan.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", plan.Unchanged)
+	}
+	if len(plan.Add) != 1 || plan.Add[0].ModelName != "gpt-5" {
+		t.Errorf("Add = %+v, want [gpt-5]", plan.Add)
+	}
+	if len(plan.Remove) != 1 || plan.Remove[0].ModelName != "gpt-3" {
+		t.Errorf("Remove = %+v, want [gpt-3]", plan.Remove)
+	}
+}
+
+func TestComputePlanIgnoresUnconfiguredBackends(t *testing.T) {
+	desired := []DesiredModelEntry{desiredEntry("gpt-4", "http://a")}
+	current := []CurrentModelEntry{currentEntry("stale", "http://unconfigured", "id-9")}
+	configured := map[string]bool{"http://a": true}
+
+	plan := computePlan(desired, current, configured)
+
+	if len(plan.Remove) != 0 {
+		t.Errorf("Remove = %+v, want none (unconfigured backend's models aren't ours to touch)", plan.Remove)
+	}
+	if len(plan.Add) != 1 {
+		t.Errorf("Add = %+v, want [gpt-4]", plan.Add)
+	}
+}
+
+func TestComputePlanSameModelDifferentBackendBothCounted(t *testing.T) {
+	desired := []DesiredModelEntry{
+		desiredEntry("gpt-4", "http://a"),
+		desiredEntry("gpt-4", "http://b"),
+	}
+	current := []CurrentModelEntry{currentEntry("gpt-4", "http://a", "id-1")}
+	configured := map[string]bool{"http://a": true, "http://b": true}
+
+	plan := computePlan(desired, current, configured)
+
+	if plan.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", plan.Unchanged)
+	}
+	if len(plan.Add) != 1 || plan.Add[0].LitellmParams.ApiBase != "http://b" {
+		t.Errorf("Add = %+v, want [gpt-4 on http://b]", plan.Add)
+	}
+}
+
+func TestPlanHasChanges(t *testing.T) {
+	cases := []struct {
+		name string
+		plan Plan
+		want bool
+	}{
+		{"empty", Plan{}, false},
+		{"only unchanged", Plan{Unchanged: 3}, false},
+		{"has add", Plan{Add: []DesiredModelEntry{desiredEntry("gpt-4", "http://a")}}, true},
+		{"has remove", Plan{Remove: []CurrentModelEntry{currentEntry("gpt-4", "http://a", "id-1")}}, true},
+	}
+	for _, c := range cases {
+		if got := c.plan.HasChanges(); got != c.want {
+			t.Errorf("%s: HasChanges() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	plan := Plan{
+		Add:       []DesiredModelEntry{desiredEntry("gpt-4", "http://a")},
+		Remove:    []CurrentModelEntry{currentEntry("gpt-3", "http://a", "id-2")},
+		Unchanged: 1,
+	}
+
+	var buf bytes.Buffer
+	if err := writePlan(&buf, plan); err != nil {
+		t.Fatalf("writePlan() returned error: %v", err)
+	}
+
+	var out planJSON
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("writePlan() did not emit valid JSON: %v", err)
+	}
+	if out.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", out.Unchanged)
+	}
+	if len(out.Add) != 1 || out.Add[0].ModelName != "gpt-4" || out.Add[0].ApiBase != "http://a" {
+		t.Errorf("Add = %+v, want [{gpt-4 http://a}]", out.Add)
+	}
+	if len(out.Remove) != 1 || out.Remove[0].ID != "id-2" {
+		t.Errorf("Remove = %+v, want [{... id-2}]", out.Remove)
+	}
+}
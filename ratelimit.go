@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// backendLimiters hands out a token-bucket rate.Limiter per backend URL,
+// created lazily on first use, so one misbehaving upstream can be throttled
+// without affecting the others.
+type backendLimiters struct {
+	qps   rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newBackendLimiters(qps float64, burst int) *backendLimiters {
+	return &backendLimiters{
+		qps:      rate.Limit(qps),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (b *backendLimiters) limiterFor(backend string) *rate.Limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.limiters[backend]
+	if !ok {
+		l = rate.NewLimiter(b.qps, b.burst)
+		b.limiters[backend] = l
+	}
+	return l
+}
+
+// Wait blocks until a request to backend is allowed to proceed, or ctx is
+// cancelled.
+func (b *backendLimiters) Wait(ctx context.Context, backend string) error {
+	return b.limiterFor(backend).Wait(ctx)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// httpStatusError carries the HTTP status code of a failed call so retry
+// logic can tell a transient 5xx from a permanent 4xx without reparsing
+// error strings.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("non-200 status: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// isRetryable reports whether err is worth retrying: network errors and
+// 5xx responses are, 4xx responses (bad request, auth, not found, ...)
+// are not, since retrying them just repeats the same failure.
+func isRetryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	return true
+}
+
+const (
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// backoffDelay returns an exponential delay for the given attempt (1-based)
+// with full jitter, capped at retryMaxDelay.
+func backoffDelay(attempt int) time.Duration {
+	shift := attempt - 1
+	if shift > 32 { // enough to blow past retryMaxDelay without overflowing the time.Duration multiply below
+		shift = 32
+	}
+	delay := retryBaseDelay * time.Duration(1<<uint(shift))
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// withRetry calls fn up to maxAttempts times, retrying only on errors
+// isRetryable considers transient, with exponential backoff and jitter
+// between attempts. op and backend are for structured logging only.
+func withRetry(ctx context.Context, backend, op string, maxAttempts int, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		start := time.Now()
+		err = fn()
+		elapsed := time.Since(start)
+
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) || attempt == maxAttempts {
+			log.Printf("op=%s backend=%s attempt=%d/%d elapsed=%s giving up: %v", op, backend, attempt, maxAttempts, elapsed, err)
+			return err
+		}
+
+		delay := backoffDelay(attempt)
+		log.Printf("op=%s backend=%s attempt=%d/%d elapsed=%s retrying in %s: %v", op, backend, attempt, maxAttempts, elapsed, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
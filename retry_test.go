@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network error", errors.New("dial tcp: connection refused"), true},
+		{"5xx", &httpStatusError{StatusCode: 503}, true},
+		{"4xx", &httpStatusError{StatusCode: 404}, false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.err); got != c.want {
+			t.Errorf("%s: isRetryable() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt <= 6; attempt++ {
+		delay := backoffDelay(attempt)
+		if delay < 0 {
+			t.Errorf("attempt %d: backoffDelay() = %s, want >= 0", attempt, delay)
+		}
+		if delay > retryMaxDelay {
+			t.Errorf("attempt %d: backoffDelay() = %s, want <= %s", attempt, delay, retryMaxDelay)
+		}
+	}
+}
+
+func TestBackoffDelayCapsAtMax(t *testing.T) {
+	// A high enough attempt must saturate at retryMaxDelay rather than
+	// overflowing time.Duration's 1<<uint(attempt-1) shift.
+	delay := backoffDelay(40)
+	if delay > retryMaxDelay {
+		t.Errorf("backoffDelay(40) = %s, want <= %s", delay, retryMaxDelay)
+	}
+}
+
+func TestWithRetrySucceedsWithoutRetry(t *testing.T) {
+	calls := 0
+	err := withRetry(context.Background(), "backend", "op", 3, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry() = %v, want nil", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestWithRetryGivesUpOnNonRetryable(t *testing.T) {
+	calls := 0
+	wantErr := &httpStatusError{StatusCode: 400}
+	err := withRetry(context.Background(), "backend", "op", 3, func() error {
+		calls++
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("withRetry() = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 (non-retryable should not retry)", calls)
+	}
+}
+
+func TestWithRetryRetriesRetryable(t *testing.T) {
+	calls := 0
+	err := withRetry(context.Background(), "backend", "op", 3, func() error {
+		calls++
+		if calls < 3 {
+			return &httpStatusError{StatusCode: 503}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry() = %v, want nil", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "sync"
+
+// runPool runs each task in tasks, at most concurrency at a time, and
+// waits for all of them to finish.
+func runPool(concurrency int, tasks []func()) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t func()) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			t()
+		}(task)
+	}
+	wg.Wait()
+}